@@ -70,6 +70,26 @@ func (t *UnixMilli) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// isByteSliceType reports whether t is []byte (as opposed to some other
+// slice type), so attr fields of that type can be base64-encoded instead of
+// falling through to the default array-of-integers JSON encoding.
+func isByteSliceType(t reflect.Type) bool {
+	return t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8
+}
+
+// byteSliceToInts renders b as a plain []int, the JSON-array-of-integers
+// form the ",raw" attr tag option opts into. encoding/json always
+// base64-encodes a []byte, even when it's boxed in an interface{}, so the
+// array form has to be spelled out with a non-byte element type.
+func byteSliceToInts(b []byte) []int {
+	ints := make([]int, len(b))
+	for i, v := range b {
+		ints[i] = int(v)
+	}
+
+	return ints
+}
+
 // func to help determine json.Marshaler implementation
 // checks both pointer and non-pointer implementations
 func isJSONMarshaler(fv reflect.Value) (json.Marshaler, bool) {
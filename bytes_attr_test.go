@@ -0,0 +1,101 @@
+package jsonapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestIsByteSliceType(t *testing.T) {
+	if !isByteSliceType(reflect.TypeOf([]byte(nil))) {
+		t.Error("expected []byte to be recognized as a byte slice type")
+	}
+	if isByteSliceType(reflect.TypeOf([]int(nil))) {
+		t.Error("did not expect []int to be recognized as a byte slice type")
+	}
+	if isByteSliceType(reflect.TypeOf("")) {
+		t.Error("did not expect string to be recognized as a byte slice type")
+	}
+}
+
+func TestByteSliceToInts(t *testing.T) {
+	got := byteSliceToInts([]byte{0x00, 0x01, 0xff})
+	want := []int{0, 1, 255}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("E=%v\n\tA=%v", want, got)
+	}
+}
+
+type rawBytesModel struct {
+	ID  string `jsonapi:"primary,raw-bytes"`
+	Key []byte `jsonapi:"attr,key,raw"`
+}
+
+func TestByteSliceRawAttrMarshalsAsIntArray(t *testing.T) {
+	m := &rawBytesModel{ID: "1", Key: []byte{0x00, 0x01, 0xff}}
+
+	payload, err := MarshalOne(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wire struct {
+		Data struct {
+			Attributes struct {
+				Key []int `json:"key"`
+			} `json:"attributes"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(b, &wire); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []int{0, 1, 255}
+	if !reflect.DeepEqual(wire.Data.Attributes.Key, want) {
+		t.Errorf("E=%v\n\tA=%v", want, wire.Data.Attributes.Key)
+	}
+}
+
+type base64BytesModel struct {
+	ID  string `jsonapi:"primary,base64-bytes"`
+	Key []byte `jsonapi:"attr,key"`
+}
+
+func TestByteSliceAttrMarshalUnmarshalRoundTrip(t *testing.T) {
+	m := &base64BytesModel{ID: "1", Key: []byte{0x00, 0x01, 0xff}}
+
+	var buf bytes.Buffer
+	if err := MarshalOnePayload(&buf, m); err != nil {
+		t.Fatal(err)
+	}
+
+	var wire struct {
+		Data struct {
+			Attributes struct {
+				Key string `json:"key"`
+			} `json:"attributes"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &wire); err != nil {
+		t.Fatal(err)
+	}
+	if wire.Data.Attributes.Key == "" {
+		t.Fatal("expected the key attribute to be a non-empty base64 string on the wire")
+	}
+
+	var got base64BytesModel
+	if err := UnmarshalPayload(bytes.NewReader(buf.Bytes()), &got); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(got.Key, m.Key) {
+		t.Errorf("Key:\n\tE=%v\n\tA=%v", m.Key, got.Key)
+	}
+}
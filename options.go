@@ -0,0 +1,58 @@
+package jsonapi
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Options configures sparse fieldsets (the JSON:API `?fields[type]=a,b,c`
+// query parameter) and the include tree (`?include=author,comments.author`)
+// for MarshalOneWithOptions and MarshalManyWithOptions.
+type Options struct {
+	// Fields, when non-nil for a given JSON:API resource type, restricts
+	// marshaling to the named attrs for nodes of that type. Types with no
+	// entry in the map are marshaled with every attr, unfiltered.
+	Fields map[string][]string
+
+	// Include lists the dotted relationship paths (e.g. "comments.author")
+	// permitted to be sideloaded and descended into. A relation outside this
+	// set is still linked, but as a shallow, linkage-only relationship. A
+	// nil Include keeps the historical behavior of sideloading every
+	// relation reachable from the root model.
+	Include []string
+}
+
+// ParseFieldsets builds an Options from URL query parameters shaped like
+// fields[articles]=title,body and include=author,comments.author, so HTTP
+// handlers can wire sparse fieldsets and includes to Marshal*WithOptions in
+// one line:
+//
+//	opts := jsonapi.ParseFieldsets(r.URL.Query())
+//	jsonapi.MarshalManyWithOptions(w, articles, opts)
+func ParseFieldsets(q url.Values) Options {
+	var opts Options
+
+	for key, vals := range q {
+		if !strings.HasPrefix(key, "fields[") || !strings.HasSuffix(key, "]") {
+			continue
+		}
+
+		typ := key[len("fields[") : len(key)-1]
+
+		var names []string
+		for _, v := range vals {
+			names = append(names, strings.Split(v, ",")...)
+		}
+
+		if opts.Fields == nil {
+			opts.Fields = make(map[string][]string)
+		}
+		opts.Fields[typ] = names
+	}
+
+	if include := q.Get("include"); include != "" {
+		opts.Include = strings.Split(include, ",")
+	}
+
+	return opts
+}
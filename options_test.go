@@ -0,0 +1,102 @@
+package jsonapi
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestParseFieldsets(t *testing.T) {
+	q, err := url.ParseQuery("fields[articles]=title,body&fields[people]=name&include=author,comments.author")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts := ParseFieldsets(q)
+
+	if got := opts.Fields["articles"]; !equalStringSlices(got, []string{"title", "body"}) {
+		t.Errorf("Fields[articles]: got %v", got)
+	}
+	if got := opts.Fields["people"]; !equalStringSlices(got, []string{"name"}) {
+		t.Errorf("Fields[people]: got %v", got)
+	}
+	if !equalStringSlices(opts.Include, []string{"author", "comments.author"}) {
+		t.Errorf("Include: got %v", opts.Include)
+	}
+}
+
+func TestFieldAllowed(t *testing.T) {
+	opts := &Options{Fields: map[string][]string{"articles": {"title"}}}
+
+	if !fieldAllowed(opts, "articles", "title") {
+		t.Error("expected title to be allowed for articles")
+	}
+	if fieldAllowed(opts, "articles", "body") {
+		t.Error("expected body to be filtered out for articles")
+	}
+	if !fieldAllowed(opts, "people", "name") {
+		t.Error("expected unfiltered type to allow every field")
+	}
+	if !fieldAllowed(nil, "articles", "body") {
+		t.Error("expected nil opts to allow every field")
+	}
+}
+
+func TestIncludeAllowed(t *testing.T) {
+	opts := &Options{Include: []string{"comments.author"}}
+
+	if !includeAllowed("comments", opts) {
+		t.Error("expected \"comments\" to be allowed as an ancestor of \"comments.author\"")
+	}
+	if !includeAllowed("comments.author", opts) {
+		t.Error("expected an exact Include match to be allowed")
+	}
+	if includeAllowed("author", opts) {
+		t.Error("did not expect an unrelated relation to be allowed")
+	}
+	if !includeAllowed("author", nil) {
+		t.Error("expected nil opts to allow every relation")
+	}
+	if !includeAllowed("author", &Options{}) {
+		t.Error("expected a nil Include to allow every relation")
+	}
+}
+
+type widgetAttrsBeforePrimary struct {
+	Title string `jsonapi:"attr,title"`
+	Body  string `jsonapi:"attr,body"`
+	ID    string `jsonapi:"primary,widgets"`
+}
+
+// TestMarshalOneWithOptionsFiltersRegardlessOfFieldOrder guards against a
+// struct declaring its attrs before its primary id field: node.Type must be
+// resolved before attrs are filtered by fieldAllowed, or sparse fieldsets
+// silently stop filtering for that struct.
+func TestMarshalOneWithOptionsFiltersRegardlessOfFieldOrder(t *testing.T) {
+	w := widgetAttrsBeforePrimary{ID: "1", Title: "a widget", Body: "secret body"}
+
+	opts := Options{Fields: map[string][]string{"widgets": {"title"}}}
+
+	node, err := visitModelNodeOpts(&w, &map[string]*Node{}, false, &opts, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := node.Attributes["body"]; ok {
+		t.Error("expected \"body\" to be filtered out by the sparse fieldset")
+	}
+	if _, ok := node.Attributes["title"]; !ok {
+		t.Error("expected \"title\" to remain, since it's listed in the sparse fieldset")
+	}
+}
+
+func equalStringSlices(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
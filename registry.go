@@ -0,0 +1,56 @@
+package jsonapi
+
+import (
+	"reflect"
+	"sync"
+)
+
+// TypeRegistry maps a JSON:API resource "type" string to the concrete Go
+// type used to represent it, so a relationship whose Go field is interface{}
+// (or a slice of interfaces) can be unmarshaled into the right concrete
+// struct instead of requiring one fixed Go type. This mirrors the
+// AnyResolver pattern jsonpb uses for google.protobuf.Any.
+//
+// A TypeRegistry is safe for concurrent use.
+type TypeRegistry struct {
+	mu    sync.RWMutex
+	types map[string]reflect.Type
+}
+
+// NewTypeRegistry returns an empty TypeRegistry.
+func NewTypeRegistry() *TypeRegistry {
+	return &TypeRegistry{types: make(map[string]reflect.Type)}
+}
+
+// RegisterType associates the JSON:API resource type name with t, so that a
+// relationship whose "type" is name can be allocated as a t during
+// UnmarshalPayloadWithRegistry. t should be a struct type, not a pointer
+// (e.g. reflect.TypeOf(Article{})).
+func (r *TypeRegistry) RegisterType(name string, t reflect.Type) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.types[name] = t
+}
+
+// New allocates a new *T (as interface{}) for the resource type name, or
+// returns (nil, false) if name hasn't been registered.
+func (r *TypeRegistry) New(name string) (interface{}, bool) {
+	r.mu.RLock()
+	t, ok := r.types[name]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, false
+	}
+
+	return reflect.New(t).Interface(), true
+}
+
+// DefaultTypeRegistry is the registry the package-level RegisterType
+// populates, for callers who only need a single process-wide registry.
+var DefaultTypeRegistry = NewTypeRegistry()
+
+// RegisterType registers name on DefaultTypeRegistry. Most callers only need
+// one registry; use TypeRegistry directly if you need more than one.
+func RegisterType(name string, t reflect.Type) {
+	DefaultTypeRegistry.RegisterType(name, t)
+}
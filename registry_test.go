@@ -0,0 +1,44 @@
+package jsonapi
+
+import (
+	"reflect"
+	"testing"
+)
+
+type registryWidget struct {
+	ID   string `jsonapi:"primary,widgets"`
+	Name string `jsonapi:"attr,name"`
+}
+
+func TestTypeRegistryNew(t *testing.T) {
+	r := NewTypeRegistry()
+	r.RegisterType("widgets", reflect.TypeOf(registryWidget{}))
+
+	instance, ok := r.New("widgets")
+	if !ok {
+		t.Fatal("expected \"widgets\" to resolve after being registered")
+	}
+	if _, ok := instance.(*registryWidget); !ok {
+		t.Errorf("expected *registryWidget, got %T", instance)
+	}
+}
+
+func TestTypeRegistryNewUnregistered(t *testing.T) {
+	r := NewTypeRegistry()
+
+	if _, ok := r.New("widgets"); ok {
+		t.Error("expected an unregistered type to not resolve")
+	}
+}
+
+func TestRegisterTypeUsesDefaultRegistry(t *testing.T) {
+	RegisterType("registry-test-gadgets", reflect.TypeOf(registryWidget{}))
+
+	instance, ok := DefaultTypeRegistry.New("registry-test-gadgets")
+	if !ok {
+		t.Fatal("expected the package-level RegisterType to populate DefaultTypeRegistry")
+	}
+	if _, ok := instance.(*registryWidget); !ok {
+		t.Errorf("expected *registryWidget, got %T", instance)
+	}
+}
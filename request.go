@@ -0,0 +1,263 @@
+package jsonapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNoData is returned by UnmarshalPayloadWithRegistry when the decoded
+// payload has no "data" member.
+var ErrNoData = errors.New("jsonapi: payload has no data")
+
+// UnmarshalPayload reads a JSON:API payload from r into dst. It's
+// UnmarshalPayloadWithRegistry with a nil registry, for the common case
+// where dst's relationships are all concrete struct types rather than
+// polymorphic interface{} fields.
+//
+// dst should be a pointer to a struct.
+func UnmarshalPayload(r io.Reader, dst interface{}) error {
+	return UnmarshalPayloadWithRegistry(r, dst, nil)
+}
+
+// UnmarshalPayloadWithRegistry reads a JSON:API payload from r into dst,
+// resolving relationships whose Go field is interface{} (or a slice of
+// interfaces) by looking up their "type" member in registry, allocating the
+// registered concrete type, and populating it from the matching "included"
+// resource. Relationships backed by a concrete struct type are populated the
+// same way without needing a registry entry.
+//
+// dst should be a pointer to a struct.
+func UnmarshalPayloadWithRegistry(r io.Reader, dst interface{}, registry *TypeRegistry) error {
+	var payload OnePayload
+	if err := json.NewDecoder(r).Decode(&payload); err != nil {
+		return err
+	}
+
+	if payload.Data == nil {
+		return ErrNoData
+	}
+
+	included := make(map[string]*Node, len(payload.Included))
+	for _, n := range payload.Included {
+		included[fmt.Sprintf("%s,%s", n.Type, n.ID)] = n
+	}
+
+	return unmarshalNode(payload.Data, reflect.ValueOf(dst).Elem(), included, registry)
+}
+
+// unmarshalNode populates the fields of modelValue, a struct, from node,
+// resolving relationships against included and, for polymorphic relationship
+// fields, registry. It is the mirror of visitModelNode.
+func unmarshalNode(node *Node, modelValue reflect.Value, included map[string]*Node, registry *TypeRegistry) error {
+	modelType := modelValue.Type()
+
+	for i := 0; i < modelValue.NumField(); i++ {
+		structField := modelType.Field(i)
+		tag := structField.Tag.Get("jsonapi")
+		if tag == "" {
+			continue
+		}
+
+		fieldValue := modelValue.Field(i)
+		args := strings.Split(tag, ",")
+
+		if len(args) < 1 {
+			return ErrBadJSONAPIStructTag
+		}
+
+		annotation := args[0]
+
+		switch annotation {
+		case "primary":
+			if err := setPrimaryField(fieldValue, node.ID); err != nil {
+				return err
+			}
+		case clientIDAnnotation:
+			fieldValue.SetString(node.ClientID)
+		case "attr":
+			raw, ok := node.Attributes[args[1]]
+			if !ok {
+				continue
+			}
+
+			var timeFormat string
+			for _, arg := range args[2:] {
+				if isTimeFormatArg(arg) {
+					timeFormat = arg
+					break
+				}
+			}
+
+			if fieldValue.Type() == reflect.TypeOf(time.Time{}) {
+				if raw == nil {
+					continue
+				}
+				t, err := decodeTimeAttr(raw, timeFormat)
+				if err != nil {
+					return err
+				}
+				fieldValue.Set(reflect.ValueOf(t))
+				continue
+			}
+
+			if fieldValue.Type() == reflect.TypeOf(new(time.Time)) {
+				if raw == nil {
+					continue
+				}
+				t, err := decodeTimeAttr(raw, timeFormat)
+				if err != nil {
+					return err
+				}
+				fieldValue.Set(reflect.ValueOf(&t))
+				continue
+			}
+
+			if s, ok := raw.(string); ok && fieldValue.CanAddr() {
+				if fs, ok := fieldValue.Addr().Interface().(FromStringer); ok {
+					if err := fs.FromString(s); err != nil {
+						return err
+					}
+					continue
+				}
+			}
+
+			b, err := json.Marshal(raw)
+			if err != nil {
+				return err
+			}
+			if err := json.Unmarshal(b, fieldValue.Addr().Interface()); err != nil {
+				return err
+			}
+		case "relation":
+			if err := unmarshalRelation(node, args[1], fieldValue, included, registry); err != nil {
+				return err
+			}
+		default:
+			return ErrBadJSONAPIStructTag
+		}
+	}
+
+	return nil
+}
+
+func setPrimaryField(fieldValue reflect.Value, id string) error {
+	switch fieldValue.Kind() {
+	case reflect.String:
+		fieldValue.SetString(id)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			return ErrBadJSONAPIID
+		}
+		fieldValue.SetInt(n)
+	case reflect.Uint64:
+		n, err := strconv.ParseUint(id, 10, 64)
+		if err != nil {
+			return ErrBadJSONAPIID
+		}
+		fieldValue.SetUint(n)
+	default:
+		return ErrBadJSONAPIID
+	}
+
+	return nil
+}
+
+// unmarshalRelation populates fieldValue, a "relation" field, from node's
+// relationship named relName.
+func unmarshalRelation(node *Node, relName string, fieldValue reflect.Value, included map[string]*Node, registry *TypeRegistry) error {
+	raw, ok := node.Relationships[relName]
+	if !ok {
+		return nil
+	}
+
+	relMap, ok := raw.(map[string]interface{})
+	if !ok {
+		return ErrBadJSONAPIStructTag
+	}
+
+	data, hasData := relMap["data"]
+	if !hasData || data == nil {
+		return nil
+	}
+
+	if fieldValue.Kind() == reflect.Slice {
+		items, ok := data.([]interface{})
+		if !ok {
+			return ErrBadJSONAPIStructTag
+		}
+
+		slice := reflect.MakeSlice(fieldValue.Type(), 0, len(items))
+		for _, item := range items {
+			elem, err := unmarshalRelationItem(item, fieldValue.Type().Elem(), included, registry)
+			if err != nil {
+				return err
+			}
+			slice = reflect.Append(slice, elem)
+		}
+
+		fieldValue.Set(slice)
+		return nil
+	}
+
+	elem, err := unmarshalRelationItem(data, fieldValue.Type(), included, registry)
+	if err != nil {
+		return err
+	}
+
+	fieldValue.Set(elem)
+	return nil
+}
+
+// unmarshalRelationItem resolves a single relationship linkage (a
+// {"type":..., "id":...} map) to a value assignable to fieldType: either the
+// registry-resolved concrete type, when fieldType is interface{}, or
+// fieldType itself, a *struct.
+func unmarshalRelationItem(data interface{}, fieldType reflect.Type, included map[string]*Node, registry *TypeRegistry) (reflect.Value, error) {
+	linkage, ok := data.(map[string]interface{})
+	if !ok {
+		return reflect.Value{}, ErrBadJSONAPIStructTag
+	}
+
+	typ, _ := linkage["type"].(string)
+	id, _ := linkage["id"].(string)
+	if typ == "" {
+		return reflect.Value{}, ErrBadJSONAPIStructTag
+	}
+
+	includedNode := included[fmt.Sprintf("%s,%s", typ, id)]
+
+	if fieldType.Kind() == reflect.Interface {
+		if registry == nil {
+			return reflect.Value{}, fmt.Errorf("jsonapi: field for relationship type %q is interface{} but no TypeRegistry was supplied", typ)
+		}
+
+		instance, ok := registry.New(typ)
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("jsonapi: no type registered for %q", typ)
+		}
+
+		if includedNode != nil {
+			if err := unmarshalNode(includedNode, reflect.ValueOf(instance).Elem(), included, registry); err != nil {
+				return reflect.Value{}, err
+			}
+		}
+
+		return reflect.ValueOf(instance), nil
+	}
+
+	instance := reflect.New(fieldType.Elem())
+	if includedNode != nil {
+		if err := unmarshalNode(includedNode, instance.Elem(), included, registry); err != nil {
+			return reflect.Value{}, err
+		}
+	}
+
+	return instance, nil
+}
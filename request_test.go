@@ -0,0 +1,150 @@
+package jsonapi
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type reqAuthor struct {
+	ID   string `jsonapi:"primary,authors"`
+	Name string `jsonapi:"attr,name"`
+}
+
+type reqConcretePost struct {
+	ID     string     `jsonapi:"primary,posts"`
+	Title  string     `jsonapi:"attr,title"`
+	Author *reqAuthor `jsonapi:"relation,author"`
+}
+
+type reqPerson struct {
+	ID   string `jsonapi:"primary,people"`
+	Name string `jsonapi:"attr,name"`
+}
+
+type reqBot struct {
+	ID      string `jsonapi:"primary,bots"`
+	Version string `jsonapi:"attr,version"`
+}
+
+type reqPolyPost struct {
+	ID        string      `jsonapi:"primary,posts"`
+	Commenter interface{} `jsonapi:"relation,commenter"`
+}
+
+type reqPolyManyPost struct {
+	ID         string        `jsonapi:"primary,posts"`
+	Commenters []interface{} `jsonapi:"relation,commenters"`
+}
+
+func TestUnmarshalPayloadWithRegistryConcreteRelation(t *testing.T) {
+	body := `{
+		"data": {
+			"type": "posts",
+			"id": "1",
+			"attributes": {"title": "hello"},
+			"relationships": {
+				"author": {"data": {"type": "authors", "id": "9"}}
+			}
+		},
+		"included": [
+			{"type": "authors", "id": "9", "attributes": {"name": "Ada"}}
+		]
+	}`
+
+	var dst reqConcretePost
+	if err := UnmarshalPayloadWithRegistry(strings.NewReader(body), &dst, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if dst.Title != "hello" {
+		t.Errorf("Title: got %q", dst.Title)
+	}
+	if dst.Author == nil || dst.Author.ID != "9" || dst.Author.Name != "Ada" {
+		t.Errorf("Author: got %+v", dst.Author)
+	}
+}
+
+func TestUnmarshalPayloadWithRegistryPolymorphicSliceRelation(t *testing.T) {
+	body := `{
+		"data": {
+			"type": "posts",
+			"id": "1",
+			"relationships": {
+				"commenters": {"data": [
+					{"type": "people", "id": "1"},
+					{"type": "bots", "id": "2"}
+				]}
+			}
+		},
+		"included": [
+			{"type": "people", "id": "1", "attributes": {"name": "Grace"}},
+			{"type": "bots", "id": "2", "attributes": {"version": "v2"}}
+		]
+	}`
+
+	registry := NewTypeRegistry()
+	registry.RegisterType("people", reflect.TypeOf(reqPerson{}))
+	registry.RegisterType("bots", reflect.TypeOf(reqBot{}))
+
+	var dst reqPolyManyPost
+	if err := UnmarshalPayloadWithRegistry(strings.NewReader(body), &dst, registry); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(dst.Commenters) != 2 {
+		t.Fatalf("expected 2 commenters, got %d", len(dst.Commenters))
+	}
+
+	person, ok := dst.Commenters[0].(*reqPerson)
+	if !ok || person.Name != "Grace" {
+		t.Errorf("Commenters[0]: got %+v", dst.Commenters[0])
+	}
+
+	bot, ok := dst.Commenters[1].(*reqBot)
+	if !ok || bot.Version != "v2" {
+		t.Errorf("Commenters[1]: got %+v", dst.Commenters[1])
+	}
+}
+
+func TestUnmarshalPayloadWithRegistryNilRegistry(t *testing.T) {
+	body := `{
+		"data": {
+			"type": "posts",
+			"id": "1",
+			"relationships": {
+				"commenter": {"data": {"type": "people", "id": "1"}}
+			}
+		}
+	}`
+
+	var dst reqPolyPost
+	err := UnmarshalPayloadWithRegistry(strings.NewReader(body), &dst, nil)
+	if err == nil {
+		t.Fatal("expected an error for an interface{} relation with no TypeRegistry")
+	}
+	if !strings.Contains(err.Error(), "no TypeRegistry was supplied") {
+		t.Errorf("got %q", err)
+	}
+}
+
+func TestUnmarshalPayloadWithRegistryUnregisteredType(t *testing.T) {
+	body := `{
+		"data": {
+			"type": "posts",
+			"id": "1",
+			"relationships": {
+				"commenter": {"data": {"type": "people", "id": "1"}}
+			}
+		}
+	}`
+
+	var dst reqPolyPost
+	err := UnmarshalPayloadWithRegistry(strings.NewReader(body), &dst, NewTypeRegistry())
+	if err == nil {
+		t.Fatal("expected an error for a relationship type with no registered Go type")
+	}
+	if !strings.Contains(err.Error(), "no type registered for") {
+		t.Errorf("got %q", err)
+	}
+}
@@ -1,6 +1,7 @@
 package jsonapi
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -82,6 +83,26 @@ func MarshalOne(model interface{}) (*OnePayload, error) {
 	return payload, nil
 }
 
+// MarshalOneWithOptions writes a jsonapi response the same way
+// MarshalOnePayload does, but honors opts' sparse fieldsets and include tree:
+// attrs not listed in opts.Fields[node.Type] are omitted, and relations not
+// reachable via opts.Include are emitted as shallow, linkage-only
+// relationships rather than being sideloaded and descended into.
+//
+// model interface{} should be a pointer to a struct.
+func MarshalOneWithOptions(w io.Writer, model interface{}, opts Options) error {
+	included := make(map[string]*Node)
+
+	rootNode, err := visitModelNodeOpts(model, &included, true, &opts, "")
+	if err != nil {
+		return err
+	}
+
+	payload := &OnePayload{Data: rootNode, Included: nodeMapValues(&included)}
+
+	return json.NewEncoder(w).Encode(payload)
+}
+
 // MarshalManyPayload writes a jsonapi response with many records, with related
 // records sideloaded, into "included" array. This method encodes a response for
 // a slice of records, hence data will be an array of records rather than a
@@ -173,6 +194,33 @@ func MarshalMany(models []interface{}) (*ManyPayload, error) {
 	return marshalMany(models, nil)
 }
 
+// MarshalManyWithOptions writes a jsonapi response the same way
+// MarshalManyPayload does, but honors opts' sparse fieldsets and include
+// tree; see MarshalOneWithOptions.
+//
+// models interface{} should be a slice of struct pointers.
+func MarshalManyWithOptions(w io.Writer, models interface{}, opts Options) error {
+	m, err := convertToSliceInterface(&models)
+	if err != nil {
+		return err
+	}
+
+	included := make(map[string]*Node)
+	data := make([]*Node, 0, len(m))
+
+	for _, model := range m {
+		node, err := visitModelNodeOpts(model, &included, true, &opts, "")
+		if err != nil {
+			return err
+		}
+		data = append(data, node)
+	}
+
+	payload := &ManyPayload{Data: data, Included: nodeMapValues(&included)}
+
+	return json.NewEncoder(w).Encode(payload)
+}
+
 func marshalMany(models []interface{}, meta interface{}) (*ManyPayload, error) {
 	var data []*Node
 	included := make(map[string]*Node)
@@ -320,12 +368,38 @@ func visitMetaNode(meta interface{}) (*map[string]interface{}, error) {
 }
 
 func visitModelNode(model interface{}, included *map[string]*Node, sideload bool) (*Node, error) {
+	return visitModelNodeOpts(model, included, sideload, nil, "")
+}
+
+// visitModelNodeOpts is visitModelNode plus sparse fieldset/include-tree
+// filtering: opts may be nil to keep the historical unfiltered behavior, and
+// includePath is the dotted relation path from the root model down to model,
+// used to test entries in opts.Include.
+func visitModelNodeOpts(model interface{}, included *map[string]*Node, sideload bool, opts *Options, includePath string) (*Node, error) {
 	node := new(Node)
 
 	var er error
 
 	modelValue := reflect.ValueOf(model).Elem()
 
+	// Resolve node.Type from the primary tag in a first pass, before the
+	// main field loop below relies on it to filter attrs via fieldAllowed.
+	// Struct fields are walked in declaration order, and a struct is free to
+	// declare its attrs before its primary id field, so node.Type can't be
+	// assumed populated by the time an attr is reached in a single pass.
+	for i := 0; i < modelValue.NumField(); i++ {
+		tag := modelValue.Type().Field(i).Tag.Get("jsonapi")
+		if tag == "" {
+			continue
+		}
+
+		args := strings.Split(tag, ",")
+		if len(args) >= 2 && args[0] == "primary" {
+			node.Type = args[1]
+			break
+		}
+	}
+
 	for i := 0; i < modelValue.NumField(); i++ {
 		structField := modelValue.Type().Field(i)
 		tag := structField.Tag.Get("jsonapi")
@@ -374,10 +448,26 @@ func visitModelNode(model interface{}, included *map[string]*Node, sideload bool
 				node.ClientID = clientID
 			}
 		} else if annotation == "attr" {
-			var omitEmpty bool
+			if !fieldAllowed(opts, node.Type, args[1]) {
+				continue
+			}
 
-			if len(args) > 2 {
-				omitEmpty = args[2] == "omitempty"
+			var omitEmpty bool
+			var timeFormat string
+			var raw bool
+			var numeric bool
+
+			for _, arg := range args[2:] {
+				switch {
+				case arg == "omitempty":
+					omitEmpty = true
+				case arg == "raw":
+					raw = true
+				case arg == "numeric":
+					numeric = true
+				case isTimeFormatArg(arg):
+					timeFormat = arg
+				}
 			}
 
 			if node.Attributes == nil {
@@ -391,7 +481,13 @@ func visitModelNode(model interface{}, included *map[string]*Node, sideload bool
 					continue
 				}
 
-				node.Attributes[args[1]] = t.Unix()
+				v, err := encodeTimeAttr(t, timeFormat)
+				if err != nil {
+					er = err
+					break
+				}
+
+				node.Attributes[args[1]] = v
 			} else if fieldValue.Type() == reflect.TypeOf(new(time.Time)) {
 				// A time pointer may be nil
 				if fieldValue.IsNil() {
@@ -407,8 +503,38 @@ func visitModelNode(model interface{}, included *map[string]*Node, sideload bool
 						continue
 					}
 
-					node.Attributes[args[1]] = tm.Unix()
+					v, err := encodeTimeAttr(*tm, timeFormat)
+					if err != nil {
+						er = err
+						break
+					}
+
+					node.Attributes[args[1]] = v
 				}
+			} else if isByteSliceType(fieldValue.Type()) && !raw {
+				b := fieldValue.Bytes()
+
+				if len(b) == 0 && omitEmpty {
+					continue
+				}
+
+				node.Attributes[args[1]] = base64.StdEncoding.EncodeToString(b)
+			} else if isByteSliceType(fieldValue.Type()) && raw {
+				b := fieldValue.Bytes()
+
+				if len(b) == 0 && omitEmpty {
+					continue
+				}
+
+				node.Attributes[args[1]] = byteSliceToInts(b)
+			} else if s, ok := integerStringerValue(fieldValue, numeric); ok {
+				emptyValue := reflect.Zero(fieldValue.Type())
+
+				if omitEmpty && fieldValue.Interface() == emptyValue.Interface() {
+					continue
+				}
+
+				node.Attributes[args[1]] = s
 			} else {
 				// Dealing with a fieldValue that is not a time
 				emptyValue := reflect.Zero(fieldValue.Type())
@@ -436,53 +562,82 @@ func visitModelNode(model interface{}, included *map[string]*Node, sideload bool
 				node.Relationships = make(map[string]interface{})
 			}
 
+			relPath := args[1]
+			if includePath != "" {
+				relPath = includePath + "." + relPath
+			}
+			descend := includeAllowed(relPath, opts)
+
 			if isSlice {
-				relationship, err := visitModelNodeRelationships(
-					args[1],
-					fieldValue,
-					included,
-					sideload,
-				)
-
-				if err == nil {
-					d := relationship.Data
-					if sideload {
-						var shallowNodes []*Node
-
-						for _, n := range d {
-							appendIncluded(included, n)
-							shallowNodes = append(shallowNodes, toShallowNode(n))
-						}
+				if !descend {
+					shallowNodes, err := shallowIdentities(fieldValue)
+					if err != nil {
+						er = err
+						break
+					}
 
-						node.Relationships[args[1]] = &RelationshipManyNode{
-							Data: shallowNodes,
+					node.Relationships[args[1]] = &RelationshipManyNode{Data: shallowNodes}
+				} else {
+					relationship, err := visitModelNodeRelationships(
+						relPath,
+						fieldValue,
+						included,
+						sideload,
+						opts,
+					)
+
+					if err == nil {
+						d := relationship.Data
+						if sideload {
+							var shallowNodes []*Node
+
+							for _, n := range d {
+								appendIncluded(included, n)
+								shallowNodes = append(shallowNodes, toShallowNode(n))
+							}
+
+							node.Relationships[args[1]] = &RelationshipManyNode{
+								Data: shallowNodes,
+							}
+						} else {
+							node.Relationships[args[1]] = relationship
 						}
 					} else {
-						node.Relationships[args[1]] = relationship
+						er = err
+						break
 					}
-				} else {
-					er = err
-					break
 				}
 			} else {
-				relationship, err := visitModelNode(
-					fieldValue.Interface(),
-					included,
-					sideload)
-				if err == nil {
-					if sideload {
-						appendIncluded(included, relationship)
-						node.Relationships[args[1]] = &RelationshipOneNode{
-							Data: toShallowNode(relationship),
+				if !descend {
+					shallow, err := shallowIdentity(fieldValue.Interface())
+					if err != nil {
+						er = err
+						break
+					}
+
+					node.Relationships[args[1]] = &RelationshipOneNode{Data: shallow}
+				} else {
+					relationship, err := visitModelNodeOpts(
+						fieldValue.Interface(),
+						included,
+						sideload,
+						opts,
+						relPath)
+					if err == nil {
+						if sideload {
+							appendIncluded(included, relationship)
+							node.Relationships[args[1]] = &RelationshipOneNode{
+								Data: toShallowNode(relationship),
+							}
+						} else {
+							node.Relationships[args[1]] = &RelationshipOneNode{
+								Data: relationship,
+							}
 						}
 					} else {
-						node.Relationships[args[1]] = &RelationshipOneNode{
-							Data: relationship,
-						}
+						er = err
+						break
 					}
-				} else {
-					er = err
-					break
 				}
 			}
 
@@ -506,7 +661,7 @@ func toShallowNode(node *Node) *Node {
 	}
 }
 
-func visitModelNodeRelationships(relationName string, models reflect.Value, included *map[string]*Node, sideload bool) (*RelationshipManyNode, error) {
+func visitModelNodeRelationships(relPath string, models reflect.Value, included *map[string]*Node, sideload bool, opts *Options) (*RelationshipManyNode, error) {
 	var nodes []*Node
 
 	if models.Len() == 0 {
@@ -515,7 +670,7 @@ func visitModelNodeRelationships(relationName string, models reflect.Value, incl
 
 	for i := 0; i < models.Len(); i++ {
 		n := models.Index(i).Interface()
-		node, err := visitModelNode(n, included, sideload)
+		node, err := visitModelNodeOpts(n, included, sideload, opts, relPath)
 		if err != nil {
 			return nil, err
 		}
@@ -526,6 +681,108 @@ func visitModelNodeRelationships(relationName string, models reflect.Value, incl
 	return &RelationshipManyNode{Data: nodes}, nil
 }
 
+// fieldAllowed reports whether the attr named name on a node of type typ
+// should be emitted. A nil opts, or a nil/missing Fields entry for typ,
+// means no sparse fieldset filtering applies to that type.
+func fieldAllowed(opts *Options, typ, name string) bool {
+	if opts == nil || opts.Fields == nil {
+		return true
+	}
+
+	names := opts.Fields[typ]
+	if names == nil {
+		return true
+	}
+
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// includeAllowed reports whether relPath, the dotted relationship path from
+// the root model down to a relation, should be descended into and
+// sideloaded. A nil opts, or a nil Include, means every relation is
+// included, matching the historical behavior. Otherwise relPath is allowed
+// when it appears in opts.Include or is an ancestor of an entry in it (e.g.
+// Include: []string{"comments.author"} also permits descending into
+// "comments").
+func includeAllowed(relPath string, opts *Options) bool {
+	if opts == nil || opts.Include == nil {
+		return true
+	}
+
+	for _, p := range opts.Include {
+		if p == relPath || strings.HasPrefix(p, relPath+".") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// shallowIdentity reflects just the "primary" field of model to build a
+// linkage-only Node, without visiting its attrs or relations. It's used for
+// relationships excluded by an Include filter, which should still surface a
+// {"type":..., "id":...} linkage even though they aren't sideloaded.
+func shallowIdentity(model interface{}) (*Node, error) {
+	v := reflect.ValueOf(model).Elem()
+	t := v.Type()
+
+	node := new(Node)
+
+	for i := 0; i < v.NumField(); i++ {
+		tag := t.Field(i).Tag.Get("jsonapi")
+		if tag == "" {
+			continue
+		}
+
+		args := strings.Split(tag, ",")
+		if args[0] != "primary" {
+			continue
+		}
+
+		id := v.Field(i).Interface()
+		switch nID := id.(type) {
+		case string:
+			node.ID = nID
+		case int:
+			node.ID = strconv.Itoa(nID)
+		case int64:
+			node.ID = strconv.FormatInt(nID, 10)
+		case uint64:
+			node.ID = strconv.FormatUint(nID, 10)
+		default:
+			return nil, ErrBadJSONAPIID
+		}
+
+		node.Type = args[1]
+		break
+	}
+
+	return node, nil
+}
+
+// shallowIdentities is shallowIdentity applied to each element of a slice of
+// related models.
+func shallowIdentities(models reflect.Value) ([]*Node, error) {
+	nodes := make([]*Node, 0, models.Len())
+
+	for i := 0; i < models.Len(); i++ {
+		n, err := shallowIdentity(models.Index(i).Interface())
+		if err != nil {
+			return nil, err
+		}
+
+		nodes = append(nodes, n)
+	}
+
+	return nodes, nil
+}
+
 func appendIncluded(m *map[string]*Node, nodes ...*Node) {
 	included := *m
 
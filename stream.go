@@ -0,0 +1,323 @@
+package jsonapi
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+)
+
+// DefaultIncludedHighWaterMark is the number of deduped included nodes an
+// Encoder buffers before flushing them out of its dedup map.
+const DefaultIncludedHighWaterMark = 1000
+
+// ErrEncoderClosed is returned by Encoder methods called after EncodeIncluded.
+var ErrEncoderClosed = errors.New("jsonapi: encoder already closed")
+
+// Encoder streams a JSON:API envelope ({"data":[...],"included":[...]}) to an
+// io.Writer one model at a time, so callers serving large or heavily related
+// collections don't have to build the full data slice and included map in
+// memory before writing anything, the way MarshalManyPayload does.
+//
+// Included records are still deduped by type and ID as they're discovered,
+// but the dedup map is periodically drained into an append-only buffer once
+// it reaches HighWaterMark entries, bounding how large that map can grow.
+//
+// An Encoder is not safe for concurrent use.
+type Encoder struct {
+	w   io.Writer
+	enc *json.Encoder
+
+	// HighWaterMark is the number of deduped entries the included map may
+	// hold before being flushed. Defaults to DefaultIncludedHighWaterMark.
+	HighWaterMark int
+
+	included map[string]*Node
+	flushed  []*Node
+
+	// seen holds the keys of every record ever moved into flushed, so a
+	// relationship rediscovered after a flush can still be recognized as a
+	// duplicate even though it's no longer in included.
+	seen map[string]struct{}
+
+	started  bool
+	wroteOne bool
+	many     bool
+	closed   bool
+}
+
+// NewEncoder returns an Encoder that writes to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{
+		w:             w,
+		enc:           json.NewEncoder(w),
+		HighWaterMark: DefaultIncludedHighWaterMark,
+		included:      make(map[string]*Node),
+		seen:          make(map[string]struct{}),
+	}
+}
+
+// EncodeOne streams a single model as the envelope's "data" object, with
+// related records sideloaded and deduped for EncodeIncluded. model should be
+// a pointer to a struct, as with MarshalOnePayload.
+func (e *Encoder) EncodeOne(model interface{}) error {
+	if e.closed {
+		return ErrEncoderClosed
+	}
+	if e.started {
+		return errors.New("jsonapi: EncodeOne called after encoding has already started")
+	}
+
+	node, err := visitModelNode(model, &e.included, true)
+	if err != nil {
+		return err
+	}
+
+	e.started = true
+	e.many = false
+
+	if _, err := io.WriteString(e.w, `{"data":`); err != nil {
+		return err
+	}
+	if err := e.enc.Encode(node); err != nil {
+		return err
+	}
+
+	e.maybeFlushIncluded()
+
+	return nil
+}
+
+// EncodeMany streams the models produced by iter as the envelope's "data"
+// array, one at a time. iter should return (model, true) for each remaining
+// model and (nil, false) once exhausted, mirroring a typical cursor loop.
+func (e *Encoder) EncodeMany(iter func() (interface{}, bool)) error {
+	if e.closed {
+		return ErrEncoderClosed
+	}
+
+	if !e.started {
+		e.started = true
+		e.many = true
+		if _, err := io.WriteString(e.w, `{"data":[`); err != nil {
+			return err
+		}
+	} else if !e.many {
+		return errors.New("jsonapi: EncodeMany called after EncodeOne")
+	}
+
+	for {
+		model, ok := iter()
+		if !ok {
+			break
+		}
+
+		node, err := visitModelNode(model, &e.included, true)
+		if err != nil {
+			return err
+		}
+
+		if e.wroteOne {
+			if _, err := io.WriteString(e.w, ","); err != nil {
+				return err
+			}
+		}
+		e.wroteOne = true
+
+		if err := e.enc.Encode(node); err != nil {
+			return err
+		}
+
+		e.maybeFlushIncluded()
+	}
+
+	return nil
+}
+
+// maybeFlushIncluded drains the dedup map into the flushed buffer once it
+// reaches HighWaterMark entries, bounding the map's memory use for streams
+// with many distinct related records.
+//
+// Before checking the water mark, it also drops any entry appendIncluded
+// re-added to included after a previous flush already moved that same
+// type/ID into flushed — appendIncluded only dedupes against the live
+// included map, so without this the record would be written into
+// "included" a second time.
+func (e *Encoder) maybeFlushIncluded() {
+	for k := range e.included {
+		if _, ok := e.seen[k]; ok {
+			delete(e.included, k)
+		}
+	}
+
+	highWaterMark := e.HighWaterMark
+	if highWaterMark <= 0 {
+		highWaterMark = DefaultIncludedHighWaterMark
+	}
+
+	if len(e.included) < highWaterMark {
+		return
+	}
+
+	for k, n := range e.included {
+		e.flushed = append(e.flushed, n)
+		e.seen[k] = struct{}{}
+	}
+	e.included = make(map[string]*Node)
+}
+
+// EncodeIncluded closes the "data" array (if EncodeMany was used) and writes
+// the deduped "included" array, then closes the envelope object. It must be
+// called exactly once, after the last EncodeOne/EncodeMany call.
+func (e *Encoder) EncodeIncluded() error {
+	if e.closed {
+		return ErrEncoderClosed
+	}
+	e.closed = true
+
+	if !e.started {
+		// Nothing was ever encoded; still produce a well-formed envelope.
+		_, err := io.WriteString(e.w, `{"data":null,"included":[]}`)
+		return err
+	}
+
+	if e.many {
+		if _, err := io.WriteString(e.w, "]"); err != nil {
+			return err
+		}
+	}
+
+	if _, err := io.WriteString(e.w, `,"included":[`); err != nil {
+		return err
+	}
+
+	wroteOne := false
+	for _, n := range e.flushed {
+		if wroteOne {
+			if _, err := io.WriteString(e.w, ","); err != nil {
+				return err
+			}
+		}
+		wroteOne = true
+		if err := e.enc.Encode(n); err != nil {
+			return err
+		}
+	}
+	for _, n := range e.included {
+		if wroteOne {
+			if _, err := io.WriteString(e.w, ","); err != nil {
+				return err
+			}
+		}
+		wroteOne = true
+		if err := e.enc.Encode(n); err != nil {
+			return err
+		}
+	}
+
+	_, err := io.WriteString(e.w, "]}")
+	return err
+}
+
+// Decoder reads a JSON:API envelope from an io.Reader, exposing DecodeNext to
+// pull one "data" element at a time so a large list response doesn't have to
+// be materialized as a single slice before a caller can start processing it,
+// mirroring jsonpb's UnmarshalNext.
+//
+// DecodeNext decodes each element into a *Node rather than a destination
+// struct; DecodeNextInto builds on the UnmarshalPayloadWithRegistry
+// reflection path to decode straight into a caller's struct instead, at the
+// cost of not resolving relationships against "included" (see its doc
+// comment for why).
+type Decoder struct {
+	dec    *json.Decoder
+	inData bool
+	done   bool
+}
+
+// NewDecoder returns a Decoder that reads from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{dec: json.NewDecoder(r)}
+}
+
+// DecodeNext returns the next element of the envelope's "data" array. It
+// returns io.EOF once every element has been consumed.
+func (d *Decoder) DecodeNext() (*Node, error) {
+	if d.done {
+		return nil, io.EOF
+	}
+
+	if !d.inData {
+		if err := d.seekToData(); err != nil {
+			return nil, err
+		}
+		d.inData = true
+	}
+
+	if !d.dec.More() {
+		if _, err := d.dec.Token(); err != nil { // consume the closing ']'
+			return nil, err
+		}
+		d.done = true
+		return nil, io.EOF
+	}
+
+	node := new(Node)
+	if err := d.dec.Decode(node); err != nil {
+		return nil, err
+	}
+
+	return node, nil
+}
+
+// DecodeNextInto decodes the next element of the envelope's "data" array
+// into dst, the way UnmarshalPayloadWithRegistry does for a single resource.
+// It returns io.EOF once every element has been consumed.
+//
+// Unlike UnmarshalPayloadWithRegistry, DecodeNextInto has no "included" map
+// to resolve relationships against: a streamed "data" array is read element
+// by element as it arrives, while an envelope's "included" array, when
+// present, only follows once "data" closes. So dst's primary and attr
+// fields are populated as usual, but relation fields are allocated without
+// being populated (not even their ID), since there's no sideloaded resource
+// yet to populate them from. Callers whose relationships need to be
+// populated should use DecodeNext and resolve "included" themselves once
+// the stream ends.
+//
+// dst should be a pointer to a struct.
+func (d *Decoder) DecodeNextInto(dst interface{}, registry *TypeRegistry) error {
+	node, err := d.DecodeNext()
+	if err != nil {
+		return err
+	}
+
+	return unmarshalNode(node, reflect.ValueOf(dst).Elem(), nil, registry)
+}
+
+// seekToData advances past envelope tokens up to and including the opening
+// '[' of the "data" array.
+func (d *Decoder) seekToData() error {
+	for {
+		tok, err := d.dec.Token()
+		if err != nil {
+			return err
+		}
+
+		key, ok := tok.(string)
+		if !ok || key != "data" {
+			continue
+		}
+
+		tok, err = d.dec.Token()
+		if err != nil {
+			return err
+		}
+
+		delim, ok := tok.(json.Delim)
+		if !ok || delim != '[' {
+			return errors.New(`jsonapi: "data" is not an array; single-resource documents aren't supported by Decoder`)
+		}
+
+		return nil
+	}
+}
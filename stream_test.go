@@ -0,0 +1,240 @@
+package jsonapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"testing"
+)
+
+type streamAuthor struct {
+	ID   string `jsonapi:"primary,authors"`
+	Name string `jsonapi:"attr,name"`
+}
+
+type streamPost struct {
+	ID     string        `jsonapi:"primary,posts"`
+	Title  string        `jsonapi:"attr,title"`
+	Author *streamAuthor `jsonapi:"relation,author"`
+}
+
+type streamEnvelope struct {
+	Data     json.RawMessage `json:"data"`
+	Included []Node          `json:"included"`
+}
+
+func TestEncodeOneEncodeIncludedRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+
+	post := &streamPost{ID: "1", Title: "hello", Author: &streamAuthor{ID: "1", Name: "Ada"}}
+	if err := enc.EncodeOne(post); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.EncodeIncluded(); err != nil {
+		t.Fatal(err)
+	}
+
+	var env streamEnvelope
+	if err := json.Unmarshal(buf.Bytes(), &env); err != nil {
+		t.Fatalf("envelope did not decode as valid JSON: %s\n%s", err, buf.String())
+	}
+
+	var data Node
+	if err := json.Unmarshal(env.Data, &data); err != nil {
+		t.Fatal(err)
+	}
+	if data.Type != "posts" || data.ID != "1" {
+		t.Errorf("data: got type=%q id=%q", data.Type, data.ID)
+	}
+
+	if len(env.Included) != 1 {
+		t.Fatalf("expected exactly one included record, got %d", len(env.Included))
+	}
+	if env.Included[0].Type != "authors" || env.Included[0].ID != "1" {
+		t.Errorf("included[0]: got type=%q id=%q", env.Included[0].Type, env.Included[0].ID)
+	}
+}
+
+func TestEncodeManyRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+
+	posts := []*streamPost{
+		{ID: "1", Title: "first", Author: &streamAuthor{ID: "1", Name: "Ada"}},
+		{ID: "2", Title: "second", Author: &streamAuthor{ID: "2", Name: "Grace"}},
+	}
+	i := 0
+	iter := func() (interface{}, bool) {
+		if i >= len(posts) {
+			return nil, false
+		}
+		p := posts[i]
+		i++
+		return p, true
+	}
+
+	if err := enc.EncodeMany(iter); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.EncodeIncluded(); err != nil {
+		t.Fatal(err)
+	}
+
+	var env streamEnvelope
+	if err := json.Unmarshal(buf.Bytes(), &env); err != nil {
+		t.Fatalf("envelope did not decode as valid JSON: %s\n%s", err, buf.String())
+	}
+
+	var data []Node
+	if err := json.Unmarshal(env.Data, &data); err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 2 {
+		t.Fatalf("expected two data records, got %d", len(data))
+	}
+	if len(env.Included) != 2 {
+		t.Fatalf("expected two included authors, got %d", len(env.Included))
+	}
+}
+
+// TestEncodeManyDedupesAcrossFlush reproduces the scenario that broke dedup:
+// two posts share an author, and HighWaterMark is set low enough that the
+// included map is flushed in between them. The shared author must still
+// appear exactly once in "included".
+func TestEncodeManyDedupesAcrossFlush(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	enc.HighWaterMark = 1
+
+	sharedAuthor := &streamAuthor{ID: "1", Name: "Ada"}
+	posts := []*streamPost{
+		{ID: "1", Title: "first", Author: sharedAuthor},
+		{ID: "2", Title: "second", Author: sharedAuthor},
+	}
+	i := 0
+	iter := func() (interface{}, bool) {
+		if i >= len(posts) {
+			return nil, false
+		}
+		p := posts[i]
+		i++
+		return p, true
+	}
+
+	if err := enc.EncodeMany(iter); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.EncodeIncluded(); err != nil {
+		t.Fatal(err)
+	}
+
+	var env streamEnvelope
+	if err := json.Unmarshal(buf.Bytes(), &env); err != nil {
+		t.Fatalf("envelope did not decode as valid JSON: %s\n%s", err, buf.String())
+	}
+
+	if len(env.Included) != 1 {
+		t.Fatalf("expected the shared author to be deduped to a single included record, got %d: %+v", len(env.Included), env.Included)
+	}
+}
+
+func TestDecoderDecodeNext(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+
+	posts := []*streamPost{
+		{ID: "1", Title: "first"},
+		{ID: "2", Title: "second"},
+	}
+	i := 0
+	iter := func() (interface{}, bool) {
+		if i >= len(posts) {
+			return nil, false
+		}
+		p := posts[i]
+		i++
+		return p, true
+	}
+
+	if err := enc.EncodeMany(iter); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.EncodeIncluded(); err != nil {
+		t.Fatal(err)
+	}
+
+	dec := NewDecoder(&buf)
+
+	var got []string
+	for {
+		node, err := dec.DecodeNext()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, node.ID)
+	}
+
+	if len(got) != 2 || got[0] != "1" || got[1] != "2" {
+		t.Errorf("expected ids [1 2], got %v", got)
+	}
+}
+
+func TestDecoderDecodeNextInto(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+
+	posts := []*streamPost{
+		{ID: "1", Title: "first", Author: &streamAuthor{ID: "9", Name: "Ada"}},
+		{ID: "2", Title: "second", Author: &streamAuthor{ID: "10", Name: "Grace"}},
+	}
+	i := 0
+	iter := func() (interface{}, bool) {
+		if i >= len(posts) {
+			return nil, false
+		}
+		p := posts[i]
+		i++
+		return p, true
+	}
+
+	if err := enc.EncodeMany(iter); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.EncodeIncluded(); err != nil {
+		t.Fatal(err)
+	}
+
+	dec := NewDecoder(&buf)
+
+	var got []streamPost
+	for {
+		var p streamPost
+		err := dec.DecodeNextInto(&p, nil)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, p)
+	}
+
+	if len(got) != 2 {
+		t.Fatalf("expected 2 posts, got %d", len(got))
+	}
+	if got[0].ID != "1" || got[0].Title != "first" {
+		t.Errorf("got[0]: %+v", got[0])
+	}
+	if got[1].ID != "2" || got[1].Title != "second" {
+		t.Errorf("got[1]: %+v", got[1])
+	}
+	// Author is allocated by unmarshalRelation but left unpopulated, since
+	// DecodeNextInto has no "included" to hydrate it from.
+	if got[0].Author == nil || got[0].Author.ID != "" {
+		t.Errorf("Author: expected an allocated but unpopulated relation, got %+v", got[0].Author)
+	}
+}
@@ -0,0 +1,58 @@
+package jsonapi
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// FromStringer is implemented by enum-like attr types that want their
+// symbolic string representation (written via fmt.Stringer; see
+// integerStringerValue) parsed back into the underlying value during
+// unmarshal, analogous to how encoding.TextUnmarshaler pairs with
+// encoding.TextMarshaler.
+type FromStringer interface {
+	FromString(string) error
+}
+
+// isIntegerKind reports whether k is one of the built-in integer kinds, the
+// set of underlying types a named enum constant is typically based on.
+func isIntegerKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// isStringer reports whether fv implements fmt.Stringer, checking both
+// pointer and non-pointer implementations.
+func isStringer(fv reflect.Value) (fmt.Stringer, bool) {
+	if s, ok := fv.Interface().(fmt.Stringer); ok {
+		return s, ok
+	}
+
+	if !fv.CanAddr() {
+		return nil, false
+	}
+
+	s, ok := fv.Addr().Interface().(fmt.Stringer)
+	return s, ok
+}
+
+// integerStringerValue returns the symbolic name for an attr field whose
+// underlying kind is an integer and which implements fmt.Stringer, unless
+// the field's tag carries the "numeric" option, which opts back into
+// encoding the raw number.
+func integerStringerValue(fv reflect.Value, numeric bool) (string, bool) {
+	if numeric || !isIntegerKind(fv.Kind()) {
+		return "", false
+	}
+
+	s, ok := isStringer(fv)
+	if !ok {
+		return "", false
+	}
+
+	return s.String(), true
+}
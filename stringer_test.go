@@ -0,0 +1,100 @@
+package jsonapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+type status int
+
+const (
+	statusActive status = iota
+	statusInactive
+)
+
+func (s status) String() string {
+	if s == statusActive {
+		return "active"
+	}
+	return "inactive"
+}
+
+func (s *status) FromString(v string) error {
+	if v == "active" {
+		*s = statusActive
+	} else {
+		*s = statusInactive
+	}
+	return nil
+}
+
+func TestIntegerStringerValue(t *testing.T) {
+	v := reflect.ValueOf(statusActive)
+
+	s, ok := integerStringerValue(v, false)
+	if !ok || s != "active" {
+		t.Errorf("got %q, %v; want \"active\", true", s, ok)
+	}
+
+	if _, ok := integerStringerValue(v, true); ok {
+		t.Error("expected the numeric option to suppress Stringer encoding")
+	}
+
+	plain := reflect.ValueOf(42)
+	if _, ok := integerStringerValue(plain, false); ok {
+		t.Error("did not expect a non-Stringer int to match")
+	}
+}
+
+func TestIsStringer(t *testing.T) {
+	s := statusActive
+	v := reflect.ValueOf(&s).Elem()
+
+	if _, ok := isStringer(v); !ok {
+		t.Error("expected status to be recognized as a fmt.Stringer")
+	}
+
+	plain := reflect.ValueOf(42)
+	if _, ok := isStringer(plain); ok {
+		t.Error("did not expect a plain int to be recognized as a fmt.Stringer")
+	}
+}
+
+type statusWidget struct {
+	ID     string `jsonapi:"primary,widgets"`
+	Status status `jsonapi:"attr,status"`
+}
+
+func TestStringerAttrMarshalUnmarshalRoundTrip(t *testing.T) {
+	widget := &statusWidget{ID: "1", Status: statusActive}
+
+	var buf bytes.Buffer
+	if err := MarshalOnePayload(&buf, widget); err != nil {
+		t.Fatal(err)
+	}
+
+	var wire struct {
+		Data struct {
+			Attributes struct {
+				Status string `json:"status"`
+			} `json:"attributes"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &wire); err != nil {
+		t.Fatal(err)
+	}
+	if wire.Data.Attributes.Status != "active" {
+		t.Errorf("Status on the wire: got %q, want \"active\"", wire.Data.Attributes.Status)
+	}
+
+	var got statusWidget
+	if err := UnmarshalPayload(bytes.NewReader(buf.Bytes()), &got); err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Status != statusActive {
+		t.Errorf("Status: got %v, want %v", got.Status, statusActive)
+	}
+}
@@ -0,0 +1,78 @@
+package jsonapi
+
+import (
+	"strings"
+	"time"
+)
+
+const layoutArgPrefix = "layout="
+
+// isTimeFormatArg reports whether arg is one of the attr tag tokens that
+// selects a time encoding, as opposed to "omitempty" or some other modifier.
+func isTimeFormatArg(arg string) bool {
+	switch arg {
+	case "iso8601", "rfc3339", "unix", "unixmilli":
+		return true
+	}
+	return strings.HasPrefix(arg, layoutArgPrefix)
+}
+
+// encodeTimeAttr renders t on the wire according to format, one of the tag
+// tokens recognized by isTimeFormatArg. An empty format preserves the
+// historical t.Unix() encoding.
+func encodeTimeAttr(t time.Time, format string) (interface{}, error) {
+	switch {
+	case format == "" || format == "unix":
+		return t.Unix(), nil
+	case format == "unixmilli":
+		return t.UnixNano() / int64(time.Millisecond), nil
+	case format == "iso8601":
+		return t.Format(iso8601Layout), nil
+	case format == "rfc3339":
+		return t.Format(time.RFC3339), nil
+	case strings.HasPrefix(format, layoutArgPrefix):
+		return t.Format(strings.TrimPrefix(format, layoutArgPrefix)), nil
+	}
+
+	return nil, ErrBadJSONAPIStructTag
+}
+
+// decodeTimeAttr parses v, a wire value produced by encodeTimeAttr under
+// format, back into a time.Time.
+func decodeTimeAttr(v interface{}, format string) (time.Time, error) {
+	switch {
+	case format == "" || format == "unix":
+		n, ok := v.(float64)
+		if !ok {
+			return time.Time{}, ErrBadJSONAPIStructTag
+		}
+		return time.Unix(int64(n), 0), nil
+	case format == "unixmilli":
+		n, ok := v.(float64)
+		if !ok {
+			return time.Time{}, ErrBadJSONAPIStructTag
+		}
+		ms := int64(n)
+		return time.Unix(ms/1000, (ms%1000)*int64(time.Millisecond)).In(time.UTC), nil
+	case format == "iso8601":
+		s, ok := v.(string)
+		if !ok {
+			return time.Time{}, ErrBadJSONAPIStructTag
+		}
+		return time.Parse(iso8601Layout, s)
+	case format == "rfc3339":
+		s, ok := v.(string)
+		if !ok {
+			return time.Time{}, ErrBadJSONAPIStructTag
+		}
+		return time.Parse(time.RFC3339, s)
+	case strings.HasPrefix(format, layoutArgPrefix):
+		s, ok := v.(string)
+		if !ok {
+			return time.Time{}, ErrBadJSONAPIStructTag
+		}
+		return time.Parse(strings.TrimPrefix(format, layoutArgPrefix), s)
+	}
+
+	return time.Time{}, ErrBadJSONAPIStructTag
+}
@@ -0,0 +1,127 @@
+package jsonapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+type timeFormatArticle struct {
+	ID          string    `jsonapi:"primary,articles"`
+	PublishedAt time.Time `jsonapi:"attr,published-at,iso8601,omitempty"`
+}
+
+func TestTimeFormatAttrMarshalUnmarshalRoundTrip(t *testing.T) {
+	when := time.Date(2017, time.April, 6, 13, 0, 0, 0, time.UTC)
+	article := &timeFormatArticle{ID: "1", PublishedAt: when}
+
+	var buf bytes.Buffer
+	if err := MarshalOnePayload(&buf, article); err != nil {
+		t.Fatal(err)
+	}
+
+	var got timeFormatArticle
+	if err := UnmarshalPayload(&buf, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	if !got.PublishedAt.Equal(when) {
+		t.Errorf("PublishedAt:\n\tE=%+v\n\tA=%+v", when, got.PublishedAt)
+	}
+}
+
+func TestEncodeTimeAttr(t *testing.T) {
+	pacific, err := time.LoadLocation("America/Los_Angeles")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	when := time.Date(2017, time.April, 6, 13, 0, 0, 0, pacific)
+
+	tests := []struct {
+		format string
+		want   interface{}
+	}{
+		{"", when.Unix()},
+		{"unix", when.Unix()},
+		{"unixmilli", when.UnixNano() / int64(time.Millisecond)},
+		{"iso8601", when.Format(iso8601Layout)},
+		{"rfc3339", when.Format(time.RFC3339)},
+		{"layout=2006-01-02", when.Format("2006-01-02")},
+	}
+
+	for _, test := range tests {
+		got, err := encodeTimeAttr(when, test.format)
+		if err != nil {
+			t.Fatalf("format %q: unexpected error: %s", test.format, err)
+		}
+		if got != test.want {
+			t.Errorf("format %q:\n\tE=%+v\n\tA=%+v", test.format, test.want, got)
+		}
+	}
+}
+
+func TestEncodeTimeAttrBadFormat(t *testing.T) {
+	if _, err := encodeTimeAttr(time.Now(), "not-a-format"); err != ErrBadJSONAPIStructTag {
+		t.Errorf("expected ErrBadJSONAPIStructTag, got %v", err)
+	}
+}
+
+func TestDecodeTimeAttrRoundTrip(t *testing.T) {
+	when := time.Date(2017, time.April, 6, 20, 0, 0, 0, time.UTC)
+
+	formats := []string{"", "unix", "unixmilli", "iso8601", "rfc3339", "layout=2006-01-02T15:04:05Z"}
+
+	for _, format := range formats {
+		encoded, err := encodeTimeAttr(when, format)
+		if err != nil {
+			t.Fatalf("format %q: encode: %s", format, err)
+		}
+
+		// Round-trip through encoding/json, as node.Attributes values do on
+		// the wire, so e.g. an int64 becomes the float64 decodeTimeAttr sees.
+		b, err := json.Marshal(encoded)
+		if err != nil {
+			t.Fatalf("format %q: marshal: %s", format, err)
+		}
+		var wireValue interface{}
+		if err := json.Unmarshal(b, &wireValue); err != nil {
+			t.Fatalf("format %q: unmarshal: %s", format, err)
+		}
+
+		got, err := decodeTimeAttr(wireValue, format)
+		if err != nil {
+			t.Fatalf("format %q: decode: %s", format, err)
+		}
+
+		if !got.Equal(when) {
+			t.Errorf("format %q:\n\tE=%+v\n\tA=%+v", format, when, got)
+		}
+	}
+}
+
+func TestDecodeTimeAttrBadValue(t *testing.T) {
+	if _, err := decodeTimeAttr(42, "iso8601"); err != ErrBadJSONAPIStructTag {
+		t.Errorf("expected ErrBadJSONAPIStructTag for a non-string iso8601 value, got %v", err)
+	}
+	if _, err := decodeTimeAttr("not-a-number", "unix"); err != ErrBadJSONAPIStructTag {
+		t.Errorf("expected ErrBadJSONAPIStructTag for a non-numeric unix value, got %v", err)
+	}
+}
+
+func TestIsTimeFormatArg(t *testing.T) {
+	positive := []string{"iso8601", "rfc3339", "unix", "unixmilli", "layout=2006-01-02"}
+	for _, arg := range positive {
+		if !isTimeFormatArg(arg) {
+			t.Errorf("expected %q to be recognized as a time format arg", arg)
+		}
+	}
+
+	negative := []string{"omitempty", "raw", ""}
+	for _, arg := range negative {
+		if isTimeFormatArg(arg) {
+			t.Errorf("did not expect %q to be recognized as a time format arg", arg)
+		}
+	}
+}